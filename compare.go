@@ -0,0 +1,206 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Comparator 用于比较两个附带了相同 version 标签定义的结构体，可以通过
+// ComparatorOption 定制部分字段的比较策略，比如 SemVer 中预发布版本号
+// "空值优先级高于非空值" 的规则。
+type Comparator struct {
+	prereleaseFields map[string]bool
+}
+
+// ComparatorOption 用于定制 Comparator 的比较行为。
+type ComparatorOption func(*Comparator)
+
+// WithPrereleaseFields 将 names 指定的字段标记为预发布类字段，这类字段
+// 在比较时空值的优先级高于非空值，其余情况再按正常的数值或字符串规则
+// 比较，与 SemVer 的预发布版本号优先级规则一致。
+func WithPrereleaseFields(names ...string) ComparatorOption {
+	return func(c *Comparator) {
+		for _, name := range names {
+			c.prereleaseFields[name] = true
+		}
+	}
+}
+
+// NewComparator 声明一个 Comparator 实例。
+func NewComparator(opt ...ComparatorOption) *Comparator {
+	c := &Comparator{prereleaseFields: make(map[string]bool, len(opt))}
+
+	for _, o := range opt {
+		o(c)
+	}
+
+	return c
+}
+
+// Compare 依次比较 a 和 b 中对应字段的值，a 小于、等于或大于 b 时，分别
+// 返回小于、等于或大于 0 的值。a 和 b 必须是附带了相同 version 标签定义
+// 的同一类型的结构体。
+func (c *Comparator) Compare(a, b interface{}) (int, error) {
+	fieldsA, err := getFields(a)
+	if err != nil {
+		return 0, err
+	}
+	fieldsB, err := getFields(b)
+	if err != nil {
+		return 0, err
+	}
+
+	index := 0
+	for {
+		fa, found := fieldsA[index]
+		if !found {
+			return 0, nil
+		}
+		fb, found := fieldsB[index]
+		if !found {
+			return 0, nil
+		}
+
+		if cmp := c.compareField(fa, fb); cmp != 0 {
+			return cmp, nil
+		}
+
+		next, found := structuralRoute(fa)
+		if !found {
+			return 0, nil
+		}
+		index = next
+	}
+}
+
+// Less 返回 a 是否小于 b。
+func (c *Comparator) Less(a, b interface{}) (bool, error) {
+	cmp, err := c.Compare(a, b)
+	return cmp < 0, err
+}
+
+// Equal 返回 a 是否等于 b。
+func (c *Comparator) Equal(a, b interface{}) (bool, error) {
+	cmp, err := c.Compare(a, b)
+	return cmp == 0, err
+}
+
+// compareField 比较 fa 和 fb 两个字段的值。
+func (c *Comparator) compareField(fa, fb *field) int {
+	if c.prereleaseFields[fa.name] {
+		return comparePrerelease(fa, fb)
+	}
+	return compareValue(fa, fb)
+}
+
+// compareValue 按字段类型比较 fa 和 fb 的值，数值按大小比较，字符串按
+// 字典序比较，idents 按其中的标识符逐一比较，多出的一方视为更大（与
+// SemVer 构建标签的比较规则一致）。
+func compareValue(fa, fb *field) int {
+	switch fa.Type {
+	case fieldTypeNumber:
+		return compareNumber(fa.Value, fb.Value)
+	case fieldTypeString:
+		return strings.Compare(fa.Value.String(), fb.Value.String())
+	case fieldTypeIdents:
+		return compareIdents(fa.Value.Interface().([]string), fb.Value.Interface().([]string))
+	default:
+		return 0
+	}
+}
+
+// compareNumber 比较 va 和 vb 两个数值字段，兼容所有有符号、无符号的
+// 整数类型，写法与 formatNumber、fieldNonZero 保持一致。
+func compareNumber(va, vb reflect.Value) int {
+	switch va.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		na, nb := va.Uint(), vb.Uint()
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		default:
+			return 0
+		}
+	default:
+		na, nb := va.Int(), vb.Int()
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		default:
+			return 0
+		}
+	}
+}
+
+// compareIdents 逐一比较 a 和 b 中的标识符，长度不同时，较短的一方在
+// 公共部分相等的情况下视为更小。
+func compareIdents(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if cmp := strings.Compare(a[i], b[i]); cmp != 0 {
+			return cmp
+		}
+	}
+
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease 按 SemVer 的预发布版本号优先级规则比较 fa 和 fb：
+// 空值的优先级高于非空值，两者都为空或都非空时，再按正常规则比较。
+func comparePrerelease(fa, fb *field) int {
+	emptyA, emptyB := !fieldNonZero(fa), !fieldNonZero(fb)
+
+	switch {
+	case emptyA && emptyB:
+		return 0
+	case emptyA:
+		return 1
+	case emptyB:
+		return -1
+	default:
+		return compareValue(fa, fb)
+	}
+}
+
+// structuralRoute 返回 f 的路由表中目标字段索引值最小的路由，即沿着
+// 字段声明的主干顺序（0、1、2……）向下走，用于在不依赖字段取值的情况
+// 下固定 Compare 遍历字段的顺序——不能按触发字符的字节值比较，触发
+// 字符的先后与字段的解析顺序并无必然联系。
+func structuralRoute(f *field) (index int, found bool) {
+	for _, idx := range f.Routes {
+		if !found || idx < index {
+			index, found = idx, true
+		}
+	}
+
+	return index, found
+}
+
+// Compare 使用默认的 Comparator 比较 a 和 b。
+func Compare(a, b interface{}) (int, error) {
+	return NewComparator().Compare(a, b)
+}
+
+// Less 使用默认的 Comparator 判断 a 是否小于 b。
+func Less(a, b interface{}) (bool, error) {
+	return NewComparator().Less(a, b)
+}
+
+// Equal 使用默认的 Comparator 判断 a 是否等于 b。
+func Equal(a, b interface{}) (bool, error) {
+	return NewComparator().Equal(a, b)
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import "testing"
+
+type semver struct {
+	Major      int      `version:"0,number,.1"`
+	Minor      int      `version:"1,number,.2"`
+	Patch      int      `version:"2,number,-3"`
+	PreRelease []string `version:"3,idents,optional,sep=."`
+}
+
+func TestCompare_idents(t *testing.T) {
+	a, b := &semver{}, &semver{}
+	if err := Parse(a, "1.2.3-alpha.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Parse(b, "1.2.3-alpha.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	cmp, err := Compare(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmp >= 0 {
+		t.Fatalf("Compare(%v, %v) = %v, want < 0", a.PreRelease, b.PreRelease, cmp)
+	}
+
+	if eq, err := Equal(a, a); err != nil || !eq {
+		t.Fatalf("Equal(a, a) = %v, %v, want true, nil", eq, err)
+	}
+}
+
+func TestCompare_prereleaseEmptyHigherThanNonEmpty(t *testing.T) {
+	release, pre := &semver{}, &semver{}
+	if err := Parse(release, "1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Parse(pre, "1.2.3-alpha.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewComparator(WithPrereleaseFields("PreRelease"))
+	less, err := c.Less(release, pre)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if less {
+		t.Fatalf("Less(release, pre) = true, want false：正式版本应该优先级更高")
+	}
+}
+
+func TestCompare_uintField(t *testing.T) {
+	a, b := &uintVersion{}, &uintVersion{}
+	if err := Parse(a, "1.2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Parse(b, "1.3"); err != nil {
+		t.Fatal(err)
+	}
+
+	less, err := Less(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !less {
+		t.Fatalf("Less(%v, %v) = false, want true", a, b)
+	}
+}
+
+func TestConstraint_idents(t *testing.T) {
+	c, err := ParseConstraint("=1.2.3-alpha.1", &semver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &semver{}
+	if err := Parse(v, "1.2.3-alpha.1"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Check(v) {
+		t.Fatalf("Check(%v) = false, want true", v)
+	}
+
+	if err := Parse(v, "1.2.3-alpha.2"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Check(v) {
+		t.Fatalf("Check(%v) = true, want false", v)
+	}
+}
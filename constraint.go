@@ -0,0 +1,384 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// operator 表示 Constraint 中单个比较项所使用的比较符。
+type operator int
+
+const (
+	opEQ operator = iota
+	opLT
+	opLTE
+	opGT
+	opGTE
+)
+
+// rangeKind 表示 ~ 和 ^ 两种范围写法。
+type rangeKind int
+
+const (
+	rangeTilde rangeKind = iota
+	rangeCaret
+)
+
+// atom 是 Constraint 中不可再分的一项比较，比如 `>=1.2.0`。
+type atom struct {
+	op      operator
+	operand interface{}
+}
+
+func (a *atom) match(v interface{}) (bool, error) {
+	cmp, err := Compare(v, a.operand)
+	if err != nil {
+		return false, err
+	}
+
+	switch a.op {
+	case opEQ:
+		return cmp == 0, nil
+	case opLT:
+		return cmp < 0, nil
+	case opLTE:
+		return cmp <= 0, nil
+	case opGT:
+		return cmp > 0, nil
+	case opGTE:
+		return cmp >= 0, nil
+	default:
+		return false, fmt.Errorf("未知道的比较符：%v", a.op)
+	}
+}
+
+// Constraint 表示由 ParseConstraint 解析出来的版本号约束表达式，
+// 可以调用 Check 判断某个版本号是否满足该约束。
+//
+// 表达式由若干以 `||` 分隔的条件组组成，组间为或的关系；每个条件组内
+// 再以 `,` 分隔若干比较项，项间为且的关系。比如：
+//  >=1.2.0,<2.0.0 || ~3.1
+// 支持的比较项有 >=、<=、>、<、=，以及 ~1.2（补丁号可变）、
+// ^1.2.3（兼容版本可变）和 1.2.x（通配符）三种范围写法。
+type Constraint struct {
+	groups [][]*atom
+}
+
+// ParseConstraint 将 expr 解析成一个 Constraint 实例，proto 用于提供
+// 版本号结构体的字段布局，其类型须与调用 Check 时传递的版本号一致。
+func ParseConstraint(expr string, proto interface{}) (*Constraint, error) {
+	t := reflect.TypeOf(proto)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	c := &Constraint{}
+
+	for _, orPart := range strings.Split(expr, "||") {
+		var group []*atom
+
+		for _, andPart := range strings.Split(orPart, ",") {
+			andPart = strings.TrimSpace(andPart)
+			if andPart == "" {
+				continue
+			}
+
+			atoms, err := parseConstraintPart(andPart, t)
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, atoms...)
+		}
+
+		if len(group) == 0 {
+			return nil, fmt.Errorf("表达式[%v]未包含任何有效的约束项", expr)
+		}
+		c.groups = append(c.groups, group)
+	}
+
+	if len(c.groups) == 0 {
+		return nil, fmt.Errorf("表达式[%v]未包含任何有效的约束项", expr)
+	}
+
+	return c, nil
+}
+
+// Check 判断 v 是否满足 c 描述的约束，v 须是与 ParseConstraint 时的
+// proto 同类型的结构体或其指针。
+func (c *Constraint) Check(v interface{}) bool {
+	for _, group := range c.groups {
+		matched := true
+		for _, a := range group {
+			ok, err := a.match(v)
+			if err != nil || !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseConstraintPart 解析单个以 `,` 分隔出来的约束项。
+func parseConstraintPart(raw string, t reflect.Type) ([]*atom, error) {
+	switch {
+	case strings.HasPrefix(raw, ">="):
+		return parseComparison(opGTE, raw[2:], t)
+	case strings.HasPrefix(raw, "<="):
+		return parseComparison(opLTE, raw[2:], t)
+	case strings.HasPrefix(raw, ">"):
+		return parseComparison(opGT, raw[1:], t)
+	case strings.HasPrefix(raw, "<"):
+		return parseComparison(opLT, raw[1:], t)
+	case strings.HasPrefix(raw, "="):
+		return parseComparison(opEQ, raw[1:], t)
+	case strings.HasPrefix(raw, "~"):
+		return parseRange(raw[1:], t, rangeTilde)
+	case strings.HasPrefix(raw, "^"):
+		return parseRange(raw[1:], t, rangeCaret)
+	case strings.ContainsAny(raw, "xX*"):
+		return parseRange(raw, t, rangeTilde)
+	default:
+		return parseComparison(opEQ, raw, t)
+	}
+}
+
+// parseComparison 解析 >=、<=、>、<、= 几种比较符对应的比较项，raw 必须
+// 是一个可以被 Parse 完整解析的版本号字符串。
+func parseComparison(op operator, raw string, t reflect.Type) ([]*atom, error) {
+	obj := reflect.New(t).Interface()
+	if err := Parse(obj, raw); err != nil {
+		return nil, err
+	}
+
+	return []*atom{{op: op, operand: obj}}, nil
+}
+
+// parseRange 解析 ~、^ 及通配符写法，它们都会展开成一对 >= 下限、< 上限
+// 的比较项。
+func parseRange(raw string, t reflect.Type, kind rangeKind) ([]*atom, error) {
+	lower, precision, err := parseOperand(raw, t)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := getFields(lower)
+	if err != nil {
+		return nil, err
+	}
+	chain := fieldChain(fields)
+
+	upper, err := upperBound(fields, chain, precision, kind, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*atom{
+		{op: opGTE, operand: lower},
+		{op: opLT, operand: upper},
+	}, nil
+}
+
+// parseOperand 将 raw 解析到一个新建的 t 类型实例中，在遇到通配符
+// （x、X 或 *）时提前结束，并返回已经成功解析的字段数量 precision。
+func parseOperand(raw string, t reflect.Type) (obj interface{}, precision int, err error) {
+	obj = reflect.New(t).Interface()
+
+	fields, err := getFields(obj)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := 0
+	index := 0
+	for i := 0; i <= len(raw); i++ {
+		f := fields[index]
+
+		if i < len(raw) {
+			switch raw[i] {
+			case 'x', 'X', '*':
+				return obj, precision, nil
+			}
+
+			nextIndex, found := f.Routes[raw[i]]
+			if !found {
+				continue
+			}
+
+			if err := setOperandFieldValue(f, raw[start:i]); err != nil {
+				return nil, 0, err
+			}
+			precision++
+			start = i + 1
+			index = nextIndex
+			continue
+		}
+
+		if start < i {
+			if err := setOperandFieldValue(f, raw[start:i]); err != nil {
+				return nil, 0, err
+			}
+			precision++
+		}
+	}
+
+	return obj, precision, nil
+}
+
+// setOperandFieldValue 将字符串 s 按 f 的类型写入其对应的字段，
+// 用于 Constraint 解析比较操作数时的简单赋值，不涉及 optional、re、
+// min、max 等校验规则。
+func setOperandFieldValue(f *field, s string) error {
+	switch f.Type {
+	case fieldTypeNumber:
+		switch f.Value.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			f.Value.SetUint(n)
+		default:
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			f.Value.SetInt(n)
+		}
+	case fieldTypeString:
+		f.Value.SetString(s)
+	case fieldTypeIdents:
+		f.Value.Set(reflect.ValueOf(strings.Split(s, f.sep)))
+	default:
+		return fmt.Errorf("未知道的 fieldType%v", f.Type)
+	}
+
+	return nil
+}
+
+// fieldChain 返回从索引 0 开始，依照路由表中目标字段索引值最小的路由
+// （见 structuralRoute）遍历得到的字段索引顺序。
+func fieldChain(fields map[int]*field) []int {
+	chain := []int{0}
+	index := 0
+	for {
+		f, ok := fields[index]
+		if !ok {
+			break
+		}
+
+		next, ok := structuralRoute(f)
+		if !ok {
+			break
+		}
+
+		chain = append(chain, next)
+		index = next
+	}
+
+	return chain
+}
+
+// upperBound 根据 ~、^ 的范围规则，计算出 precision 个字段已确定的情况
+// 下对应的排他上限。
+func upperBound(fields map[int]*field, chain []int, precision int, kind rangeKind, t reflect.Type) (interface{}, error) {
+	if precision <= 0 {
+		precision = 1
+	}
+	if precision > len(chain) {
+		precision = len(chain)
+	}
+
+	obj := reflect.New(t).Interface()
+	upperFields, err := getFields(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	bumpPos := 0
+	switch kind {
+	case rangeTilde:
+		if precision > 1 {
+			bumpPos = 1
+		}
+	case rangeCaret:
+		bumpPos = precision - 1
+		for i := 0; i < precision; i++ {
+			if !isZeroField(fields[chain[i]]) {
+				bumpPos = i
+				break
+			}
+		}
+	}
+
+	for i, idx := range chain {
+		f := upperFields[idx]
+		switch {
+		case i < bumpPos:
+			copyFieldValue(f, fields[idx])
+		case i == bumpPos:
+			copyFieldValue(f, fields[idx])
+			bumpFieldValue(f)
+		default:
+			// 保持零值
+		}
+	}
+
+	return obj, nil
+}
+
+// isZeroField 判断 f 的当前值是否为该类型的零值。
+func isZeroField(f *field) bool {
+	return !fieldNonZero(f)
+}
+
+// copyFieldValue 将 src 的值复制到 dst。
+func copyFieldValue(dst, src *field) {
+	switch src.Type {
+	case fieldTypeNumber:
+		switch src.Value.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dst.Value.SetUint(src.Value.Uint())
+		default:
+			dst.Value.SetInt(src.Value.Int())
+		}
+	case fieldTypeString:
+		dst.Value.SetString(src.Value.String())
+	case fieldTypeIdents:
+		idents := src.Value.Interface().([]string)
+		dst.Value.Set(reflect.ValueOf(append([]string(nil), idents...)))
+	}
+}
+
+// bumpFieldValue 将 f 的值加一，用于计算 ~、^ 范围的排他上限。
+func bumpFieldValue(f *field) {
+	switch f.Type {
+	case fieldTypeNumber:
+		switch f.Value.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f.Value.SetUint(f.Value.Uint() + 1)
+		default:
+			f.Value.SetInt(f.Value.Int() + 1)
+		}
+	case fieldTypeString:
+		// 字符串字段没有明确的 "加一" 语义，约定追加一个排序上不可能
+		// 由正常版本号产生的字节，以保证上限严格大于所有同前缀的值。
+		f.Value.SetString(f.Value.String() + "\xff")
+	case fieldTypeIdents:
+		// idents 字段同样没有明确的 "加一" 语义，约定追加一个排序上
+		// 不可能由正常版本号产生的标识符，保证上限严格大于所有同前缀
+		// 的值。
+		idents := f.Value.Interface().([]string)
+		f.Value.Set(reflect.ValueOf(append(append([]string(nil), idents...), "\xff")))
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import "testing"
+
+func TestConstraint_comparison(t *testing.T) {
+	cases := []struct {
+		expr string
+		ver  string
+		want bool
+	}{
+		{expr: ">=1.2.0", ver: "1.2.0", want: true},
+		{expr: ">=1.2.0", ver: "1.1.9", want: false},
+		{expr: "<=1.2.0", ver: "1.2.0", want: true},
+		{expr: "<=1.2.0", ver: "1.2.1", want: false},
+		{expr: ">1.2.0", ver: "1.2.1", want: true},
+		{expr: ">1.2.0", ver: "1.2.0", want: false},
+		{expr: "<1.2.0", ver: "1.1.9", want: true},
+		{expr: "<1.2.0", ver: "1.2.0", want: false},
+		{expr: ">=1.2.0,<2.0.0", ver: "1.9.9", want: true},
+		{expr: ">=1.2.0,<2.0.0", ver: "2.0.0", want: false},
+		{expr: "<1.0.0 || >=2.0.0", ver: "2.5.0", want: true},
+		{expr: "<1.0.0 || >=2.0.0", ver: "1.5.0", want: false},
+	}
+
+	for _, c := range cases {
+		constraint, err := ParseConstraint(c.expr, &docVersion{})
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q): %v", c.expr, err)
+		}
+
+		v := &docVersion{}
+		if err := Parse(v, c.ver); err != nil {
+			t.Fatalf("Parse(%q): %v", c.ver, err)
+		}
+
+		if got := constraint.Check(v); got != c.want {
+			t.Fatalf("Check(%q) 对表达式[%v] = %v, want %v", c.ver, c.expr, got, c.want)
+		}
+	}
+}
+
+func TestConstraint_range(t *testing.T) {
+	cases := []struct {
+		expr string
+		ver  string
+		want bool
+	}{
+		{expr: "~1.2.3", ver: "1.2.9", want: true},
+		{expr: "~1.2.3", ver: "1.3.0", want: false},
+		{expr: "^1.2.3", ver: "1.9.0", want: true},
+		{expr: "^1.2.3", ver: "2.0.0", want: false},
+		{expr: "1.2.x", ver: "1.2.9", want: true},
+		{expr: "1.2.x", ver: "1.3.0", want: false},
+	}
+
+	for _, c := range cases {
+		constraint, err := ParseConstraint(c.expr, &docVersion{})
+		if err != nil {
+			t.Fatalf("ParseConstraint(%q): %v", c.expr, err)
+		}
+
+		v := &docVersion{}
+		if err := Parse(v, c.ver); err != nil {
+			t.Fatalf("Parse(%q): %v", c.ver, err)
+		}
+
+		if got := constraint.Check(v); got != c.want {
+			t.Fatalf("Check(%q) 对表达式[%v] = %v, want %v", c.ver, c.expr, got, c.want)
+		}
+	}
+}
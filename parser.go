@@ -0,0 +1,217 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Parser 预先解析并缓存了某个版本号结构体类型的 version 标签元数据，可以
+// 重复使用以避免每次 Parse 都重新反射 tag，适合需要连续解析大量版本号
+// 字符串的场景，比如服务端校验请求中携带的版本号参数。
+//
+// 官方文档也提到，基于 reflect 的代码通常比手写代码慢一到两个数量级，
+// Parser 通过缓存 tag 反射的结果规避了这部分开销。
+type Parser struct {
+	typ       reflect.Type
+	fields    map[int]*schemaField
+	allNumber bool // 所有字段都是 fieldTypeNumber 时为 true，用于启用快速路径
+}
+
+// NewParser 根据 proto 的 version 标签定义创建一个 Parser，proto 可以是
+// 结构体或是指向结构体的指针。
+func NewParser(proto interface{}) (*Parser, error) {
+	t := reflect.TypeOf(proto)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("参数 proto 只能是结构体")
+	}
+
+	fields, err := parseSchema(t)
+	if err != nil {
+		return nil, err
+	}
+
+	allNumber := true
+	for _, f := range fields {
+		if f.Type != fieldTypeNumber {
+			allNumber = false
+			break
+		}
+	}
+
+	return &Parser{typ: t, fields: fields, allNumber: allNumber}, nil
+}
+
+// Parse 使用 p 缓存的字段元数据解析 ver，并将结果写入 out。out 必须是
+// 与创建 p 时的 proto 相同类型的结构体指针。
+func (p *Parser) Parse(ver string, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Type() != p.typ {
+		return fmt.Errorf("out 必须是 %v 的指针", p.typ)
+	}
+	v = v.Elem()
+
+	visited := make(map[int]bool, len(p.fields))
+	start := 0
+	index := 0
+	for i := 0; i < len(ver)+1; i++ {
+		f := p.fields[index]
+
+		var nextIndex int
+		var found bool
+		if i < len(ver) {
+			nextIndex, found = f.Routes[ver[i]]
+			if !found {
+				continue
+			}
+		}
+
+		if err := p.setValue(f, v.Field(f.structIndex), ver[start:i], start); err != nil {
+			return err
+		}
+		visited[index] = true
+
+		if i >= len(ver) {
+			break
+		}
+
+		i++
+		start = i
+		index = nextIndex
+	}
+
+	return p.applyDefaults(v, visited)
+}
+
+// setValue 校验并将 s 写入 fv，offset 为 s 在原始版本号字符串中的起始
+// 位置，用于构造 ParseError。
+func (p *Parser) setValue(f *schemaField, fv reflect.Value, s string, offset int) error {
+	if f.custom {
+		if err := callUnmarshaler(f.name, fv, s); err != nil {
+			return &ParseError{Field: f.name, Offset: offset, Rule: "custom", Err: err}
+		}
+		return nil
+	}
+
+	if s == "" && f.optional {
+		if f.hasDefault {
+			return setSchemaDefaultValue(f, fv)
+		}
+		return nil
+	}
+
+	switch f.Type {
+	case fieldTypeNumber:
+		if p.allNumber && fv.Kind() != reflect.Uint && fv.Kind() != reflect.Uint8 &&
+			fv.Kind() != reflect.Uint16 && fv.Kind() != reflect.Uint32 && fv.Kind() != reflect.Uint64 {
+			n, err := scanInt64(s)
+			if err != nil {
+				return &ParseError{Field: f.name, Offset: offset, Rule: "type", Err: err}
+			}
+			if f.hasMin && n < f.min {
+				return &ParseError{Field: f.name, Offset: offset, Rule: "min", Err: fmt.Errorf("值[%v]小于最小值[%v]", n, f.min)}
+			}
+			if f.hasMax && n > f.max {
+				return &ParseError{Field: f.name, Offset: offset, Rule: "max", Err: fmt.Errorf("值[%v]大于最大值[%v]", n, f.max)}
+			}
+			fv.SetInt(n)
+			return nil
+		}
+
+		if err := setNumberValue(fv, s, f.validator); err != nil {
+			return &ParseError{Field: f.name, Offset: offset, Rule: err.rule, Err: err.err}
+		}
+	case fieldTypeString:
+		if f.re != nil && !f.re.MatchString(s) {
+			return &ParseError{Field: f.name, Offset: offset, Rule: "re", Err: fmt.Errorf("值[%v]不匹配正则[%v]", s, f.re.String())}
+		}
+		fv.SetString(s)
+	case fieldTypeIdents:
+		fv.Set(reflect.ValueOf(strings.Split(s, f.sep)))
+	default:
+		return fmt.Errorf("未知道的 fieldType%v", f.Type)
+	}
+
+	return nil
+}
+
+// setSchemaDefaultValue 将 fv 设置为 f 的 default= 指令指定的值。
+func setSchemaDefaultValue(f *schemaField, fv reflect.Value) error {
+	switch f.Type {
+	case fieldTypeNumber:
+		if err := setNumberValue(fv, f.defaultStr, f.validator); err != nil {
+			return &ParseError{Field: f.name, Rule: "default", Err: err.err}
+		}
+	case fieldTypeString:
+		fv.SetString(f.defaultStr)
+	case fieldTypeIdents:
+		fv.Set(reflect.ValueOf(strings.Split(f.defaultStr, f.sep)))
+	}
+
+	return nil
+}
+
+// applyDefaults 为解析过程中未被访问到的字段应用 default= 指令指定的
+// 默认值。
+func (p *Parser) applyDefaults(v reflect.Value, visited map[int]bool) error {
+	for index, f := range p.fields {
+		if visited[index] || !f.hasDefault {
+			continue
+		}
+		if err := setSchemaDefaultValue(f, v.Field(f.structIndex)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanInt64 直接扫描 s 中的数字字符得到对应的 int64 值，在 Parser 的所有
+// 字段都是 number 类型时代替 strconv.ParseInt，省去其内部进制判断等
+// 通用逻辑，但仍需识别开头的负号，以便与 strconv.ParseInt 的取值范围
+// 保持一致。
+func scanInt64(s string) (int64, error) {
+	if len(s) == 0 {
+		return 0, fmt.Errorf("无法将空字符串转换成数值")
+	}
+
+	neg := false
+	if s[0] == '-' || s[0] == '+' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return 0, fmt.Errorf("无法将空字符串转换成数值")
+	}
+
+	// 绝对值上限：正数为 1<<63-1，负数为 1<<63。
+	limit := uint64(1<<63 - 1)
+	if neg {
+		limit++
+	}
+
+	var n uint64
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("%v 不是合法的数值", s)
+		}
+		d := uint64(c - '0')
+		if n > (limit-d)/10 {
+			return 0, fmt.Errorf("%v 超出了 int64 的取值范围", s)
+		}
+		n = n*10 + d
+	}
+
+	if neg {
+		return -int64(n), nil
+	}
+	return int64(n), nil
+}
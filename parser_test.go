@@ -0,0 +1,37 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import "testing"
+
+type offsetVersion struct {
+	Major int `version:"0,number,.1"`
+	Minor int `version:"1,number"`
+}
+
+func TestParser_matchesParse(t *testing.T) {
+	cases := []string{"1.2", "-1.2", "1.-2", "-1.-2"}
+
+	p, err := NewParser(&offsetVersion{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ver := range cases {
+		want := &offsetVersion{}
+		if err := Parse(want, ver); err != nil {
+			t.Fatalf("Parse(%q): %v", ver, err)
+		}
+
+		got := &offsetVersion{}
+		if err := p.Parse(ver, got); err != nil {
+			t.Fatalf("Parser.Parse(%q): %v", ver, err)
+		}
+
+		if *got != *want {
+			t.Fatalf("Parser.Parse(%q) = %+v, want %+v", ver, got, want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// validator 保存字段除 index、type、route 之外的几个校验相关的指令：
+//  - default=X 字段因字符串提前结束而未出现时使用的默认值；
+//  - optional   对应的子字符串为空时不报错；
+//  - re=<pattern> 字符串字段必须匹配的正则表达式；
+//  - min=N、max=N 数值字段的取值范围。
+type validator struct {
+	optional   bool
+	hasDefault bool
+	defaultStr string
+	re         *regexp.Regexp
+	hasMin     bool
+	min        int64
+	hasMax     bool
+	max        int64
+}
+
+// ParseError 表示某个字段未能满足 version 标签中校验规则时返回的错误，
+// 包含了出错的字段名称、该字段内容在原始版本号字符串中的起始偏移量，
+// 以及触发的规则名称。
+type ParseError struct {
+	Field  string // 字段名称
+	Offset int    // 字段内容在原始版本号字符串中的起始位置
+	Rule   string // 触发的规则，比如 type、min、max、re、default
+	Err    error  // 原始错误
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("字段[%v]在偏移量[%v]处未满足规则[%v]：%v", e.Field, e.Offset, e.Rule, e.Err)
+}
+
+// Unwrap 返回触发该错误的原始错误，以便使用 errors.Is、errors.As 判断。
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// parseValidatorTag 尝试将 tag 作为 default=、optional、re=、min=、max=、
+// sep= 几种指令之一解析到 sf 中。ok 为 false 表示 tag 不属于这几种指令，
+// 调用方应将其按路由解析。
+func parseValidatorTag(sf *schemaField, fieldName, tag string) (ok bool, err error) {
+	switch {
+	case tag == "optional":
+		sf.optional = true
+		return true, nil
+	case strings.HasPrefix(tag, "default="):
+		sf.hasDefault = true
+		sf.defaultStr = tag[len("default="):]
+		return true, nil
+	case strings.HasPrefix(tag, "re="):
+		pattern := tag[len("re="):]
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return true, fmt.Errorf("字段[%v]的 re 指令无效：%v", fieldName, err)
+		}
+		sf.re = re
+		return true, nil
+	case strings.HasPrefix(tag, "min="):
+		n, err := strconv.ParseInt(tag[len("min="):], 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("字段[%v]的 min 指令无效：%v", fieldName, err)
+		}
+		sf.hasMin = true
+		sf.min = n
+		return true, nil
+	case strings.HasPrefix(tag, "max="):
+		n, err := strconv.ParseInt(tag[len("max="):], 10, 64)
+		if err != nil {
+			return true, fmt.Errorf("字段[%v]的 max 指令无效：%v", fieldName, err)
+		}
+		sf.hasMax = true
+		sf.max = n
+		return true, nil
+	case strings.HasPrefix(tag, "sep="):
+		sf.sep = tag[len("sep="):]
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Unmarshaler 由需要自行控制解析过程的字段类型实现。字段对应的 Go
+// 类型一旦实现了该接口，Parse 即交由 UnmarshalVersion 处理该字段对应
+// 的子字符串，不再理会 tags[1] 中声明的 number、string 或 idents 类型。
+type Unmarshaler interface {
+	UnmarshalVersion(s string) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// setFieldValue 校验并设置 f 对应的值为 s，offset 为 s 在原始版本号
+// 字符串中的起始位置，用于构造 ParseError。
+func setFieldValue(f *field, s string, offset int) error {
+	if f.custom {
+		if err := callUnmarshaler(f.name, f.Value, s); err != nil {
+			return &ParseError{Field: f.name, Offset: offset, Rule: "custom", Err: err}
+		}
+		return nil
+	}
+
+	if s == "" && f.optional {
+		if f.hasDefault {
+			return setDefaultValue(f)
+		}
+		return nil
+	}
+
+	switch f.Type {
+	case fieldTypeNumber:
+		if err := setNumberValue(f.Value, s, f.validator); err != nil {
+			return &ParseError{Field: f.name, Offset: offset, Rule: err.rule, Err: err.err}
+		}
+	case fieldTypeString:
+		if f.re != nil && !f.re.MatchString(s) {
+			return &ParseError{Field: f.name, Offset: offset, Rule: "re", Err: fmt.Errorf("值[%v]不匹配正则[%v]", s, f.re.String())}
+		}
+		f.Value.SetString(s)
+	case fieldTypeIdents:
+		f.Value.Set(reflect.ValueOf(strings.Split(s, f.sep)))
+	default:
+		return fmt.Errorf("未知道的 fieldType%v", f.Type)
+	}
+
+	return nil
+}
+
+// numberError 描述 setNumberValue 中触发的规则，便于调用方构造带字段
+// 名称和偏移量的 ParseError。
+type numberError struct {
+	rule string
+	err  error
+}
+
+func (e *numberError) Error() string { return e.err.Error() }
+
+// setNumberValue 将 s 写入 fv，fv 可以是任意有符号或无符号的整数类型，
+// 并应用 v 中的 min、max 规则。
+func setNumberValue(fv reflect.Value, s string, v validator) *numberError {
+	switch fv.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return &numberError{rule: "type", err: err}
+		}
+		if v.hasMin && int64(n) < v.min {
+			return &numberError{rule: "min", err: fmt.Errorf("值[%v]小于最小值[%v]", n, v.min)}
+		}
+		if v.hasMax && int64(n) > v.max {
+			return &numberError{rule: "max", err: fmt.Errorf("值[%v]大于最大值[%v]", n, v.max)}
+		}
+		fv.SetUint(n)
+	default:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return &numberError{rule: "type", err: err}
+		}
+		if v.hasMin && n < v.min {
+			return &numberError{rule: "min", err: fmt.Errorf("值[%v]小于最小值[%v]", n, v.min)}
+		}
+		if v.hasMax && n > v.max {
+			return &numberError{rule: "max", err: fmt.Errorf("值[%v]大于最大值[%v]", n, v.max)}
+		}
+		fv.SetInt(n)
+	}
+
+	return nil
+}
+
+// callUnmarshaler 调用 v 对应类型实现的 Unmarshaler 接口解析 s，
+// name 仅用于在 v 不可寻址或未实现该接口时给出更明确的错误提示。
+func callUnmarshaler(name string, v reflect.Value, s string) error {
+	if !v.CanAddr() {
+		return fmt.Errorf("字段[%v]的值不可寻址，无法调用 UnmarshalVersion", name)
+	}
+
+	u, ok := v.Addr().Interface().(Unmarshaler)
+	if !ok {
+		return fmt.Errorf("字段[%v]未实现 Unmarshaler 接口", name)
+	}
+
+	return u.UnmarshalVersion(s)
+}
+
+// setDefaultValue 将 f 的值设置为其 default= 指令指定的值。
+func setDefaultValue(f *field) error {
+	switch f.Type {
+	case fieldTypeNumber:
+		if err := setNumberValue(f.Value, f.defaultStr, f.validator); err != nil {
+			return &ParseError{Field: f.name, Rule: "default", Err: err.err}
+		}
+	case fieldTypeString:
+		f.Value.SetString(f.defaultStr)
+	case fieldTypeIdents:
+		f.Value.Set(reflect.ValueOf(strings.Split(f.defaultStr, f.sep)))
+	}
+
+	return nil
+}
+
+// applyDefaults 为解析过程中未被访问到的字段（即字符串提前结束，还没
+// 到达该字段就已结束）应用 default= 指令指定的默认值。
+func applyDefaults(fields map[int]*field, visited map[int]bool) error {
+	for index, f := range fields {
+		if visited[index] || !f.hasDefault {
+			continue
+		}
+		if err := setDefaultValue(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import "testing"
+
+type taggedVersion struct {
+	Major int    `version:"0,number,min=1,max=99,.1"`
+	Stage string `version:"1,string,optional,default=stable,re=^[a-z]+$"`
+}
+
+func TestParseValidatorTag_minMax(t *testing.T) {
+	v := &taggedVersion{}
+	if err := Parse(v, "1.alpha"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Parse(&taggedVersion{}, "0.alpha"); err == nil {
+		t.Fatal("Parse(\"0.alpha\") 未触发 min 规则")
+	}
+
+	if err := Parse(&taggedVersion{}, "100.alpha"); err == nil {
+		t.Fatal("Parse(\"100.alpha\") 未触发 max 规则")
+	}
+}
+
+func TestParseValidatorTag_re(t *testing.T) {
+	if err := Parse(&taggedVersion{}, "1.ALPHA"); err == nil {
+		t.Fatal("Parse(\"1.ALPHA\") 未触发 re 规则")
+	}
+}
+
+func TestParseValidatorTag_optionalDefault(t *testing.T) {
+	v := &taggedVersion{}
+	if err := Parse(v, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if v.Stage != "stable" {
+		t.Fatalf("Stage = %q, want %q", v.Stage, "stable")
+	}
+}
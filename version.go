@@ -6,7 +6,11 @@
 //
 // version 通过 struct tag 的相关定义来解析版本号字符串。包含了以下标签。
 // - index 该字段对应的的编号，也是默认的解析顺序(0 是入口)，只能为整数，唯一；
-// - type 该字段的类型，可以值为 number(数字)、string(字符串)；
+// - type 该字段的类型，可以值为 number(数字，支持所有有符号、无符号
+// 整数类型)、string(字符串)、idents(以 sep 指令指定的分隔符分隔的
+// 字符串列表，对应 []string 类型，sep 默认为 `.`)；字段对应的 Go
+// 类型实现了 Unmarshaler 接口时，则由该接口接管解析，此时 type 的
+// 取值不再生效；
 // - route 表示当前字段的结束字符，以及对应的需要跳转到的索引值值。
 // 比如以下定义的结构体：
 //  type struct Version {
@@ -23,6 +27,7 @@
 package version
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"reflect"
@@ -31,18 +36,22 @@ import (
 	"unicode"
 )
 
-// 表示结构体字段的类型，版本号要嘛是字符串，要嘛是数值
+// 表示结构体字段的类型
 const (
-	fieldTypeNumber = iota
-	fieldTypeString
+	fieldTypeNumber = iota // 数值，包含所有有符号、无符号的整数类型
+	fieldTypeString        // 字符串
+	fieldTypeIdents        // 以分隔符分隔的字符串列表，对应 []string 类型
 )
 
 // 对每个字段的描述
 type field struct {
 	name   string        // 字段名称
-	Type   int           // 该字段的类型，数值或是字符串
+	Type   int           // 该字段的类型，数值、字符串或是 idents
 	Routes map[byte]int  // 该字段的路由，根据不同的字符，会跳到不同的元素中解析
 	Value  reflect.Value // 该字段的 reflect.Value 类型，方便设置值。
+	sep    string        // fieldTypeIdents 类型的分隔符
+	custom bool          // 字段类型是否实现了 Unmarshaler 接口
+	validator
 }
 
 // Parse 解析版本号字符串到 obj 中。
@@ -52,7 +61,9 @@ func Parse(obj interface{}, ver string) error {
 		return err
 	}
 
+	visited := make(map[int]bool, len(fields))
 	start := 0
+	index := 0
 	field := fields[0]
 	for i := 0; i < len(ver)+1; i++ {
 		var nextIndex int
@@ -66,25 +77,144 @@ func Parse(obj interface{}, ver string) error {
 			}
 		}
 
-		switch field.Type {
-		case fieldTypeNumber:
-			n, err := strconv.ParseInt(ver[start:i], 10, 64)
-			if err != nil {
-				return err
-			}
-			field.Value.SetInt(n)
-		case fieldTypeString:
-			field.Value.SetString(ver[start:i])
-		default:
-			return errors.New("未知道的 fieldType" + strconv.Itoa(field.Type))
+		if err := setFieldValue(field, ver[start:i], start); err != nil {
+			return err
+		}
+		visited[index] = true
+
+		if i >= len(ver) {
+			break
 		}
 
 		i++ // 过滤掉当前字符
 		start = i
-		field = fields[nextIndex] // 下一个 field
+		index = nextIndex
+		field = fields[index] // 下一个 field
 	} // end for
 
-	return nil
+	return applyDefaults(fields, visited)
+}
+
+// Format 将 obj 格式化成版本号字符串，是 Parse 的逆操作。
+func Format(obj interface{}) (string, error) {
+	fields, err := getFields(obj)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	index := 0
+	for {
+		f, found := fields[index]
+		if !found {
+			return "", fmt.Errorf("索引值[%v]不存在对应的字段", index)
+		}
+
+		switch f.Type {
+		case fieldTypeNumber:
+			buf.WriteString(formatNumber(f.Value))
+		case fieldTypeString:
+			buf.WriteString(f.Value.String())
+		case fieldTypeIdents:
+			buf.WriteString(strings.Join(f.Value.Interface().([]string), f.sep))
+		default:
+			return "", errors.New("未知道的 fieldType" + strconv.Itoa(f.Type))
+		}
+
+		next, trigger, ok := nextRoute(fields, f)
+		if !ok {
+			break
+		}
+		buf.WriteByte(trigger)
+		index = next
+	}
+
+	return buf.String(), nil
+}
+
+// formatNumber 将数值字段格式化成字符串，兼容所有有符号、无符号的
+// 整数类型。
+func formatNumber(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	default:
+		return strconv.FormatInt(v.Int(), 10)
+	}
+}
+
+// fieldNonZero 判断 f 的当前值是否为其类型的非零值。
+func fieldNonZero(f *field) bool {
+	switch f.Type {
+	case fieldTypeNumber:
+		switch f.Value.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return f.Value.Uint() != 0
+		default:
+			return f.Value.Int() != 0
+		}
+	case fieldTypeString:
+		return f.Value.String() != ""
+	case fieldTypeIdents:
+		return f.Value.Len() != 0
+	default:
+		return false
+	}
+}
+
+// branchShouldEmit 递归判断从 index 开始的分支，在格式化时是否应该被
+// 写出：分支中只要有一个字段是必填的（未带 optional 指令），或是取值
+// 非零/非空，就需要写出整条分支；反之，分支中的字段全部是"可选且当前
+// 为零值/空值"（比如未出现的 PreRelease），才视为没有内容，可以跳过
+// 整条分支对应的触发字符。visited 用于防止路由表出现环路时无限递归。
+func branchShouldEmit(fields map[int]*field, index int, visited map[int]bool) bool {
+	if visited[index] {
+		return false
+	}
+	visited[index] = true
+
+	f, ok := fields[index]
+	if !ok {
+		return false
+	}
+
+	if !f.optional || fieldNonZero(f) {
+		return true
+	}
+
+	for _, idx := range f.Routes {
+		if branchShouldEmit(fields, idx, visited) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nextRoute 根据 f 的路由表，选出格式化时应该跳转的下一个字段。
+// 优先选择分支中应该被写出的路由（见 branchShouldEmit），而不仅仅判断
+// 目标字段自身是否非零，否则会出现诸如"1.0.3"（Minor 恰好为 0，但属于
+// 必填字段）这样的版本号被误判为可以跳过 Minor 直接走向 Build 的情况。
+// 有多个路由同时符合条件时，取目标字段索引值最小的一项，即沿着字段
+// 声明的主干顺序（0、1、2……）向下走，而不是按触发字符的字节值比较——
+// 触发字符的先后与字段的解析顺序并无必然联系。若没有任何路由符合
+// 条件（比如分支末尾只是一个未出现的可选字段），则 found 返回 false，
+// 不再写出该分支。f 没有路由时 found 也返回 false。
+func nextRoute(fields map[int]*field, f *field) (index int, trigger byte, found bool) {
+	if len(f.Routes) == 0 {
+		return 0, 0, false
+	}
+
+	for b, idx := range f.Routes {
+		if !branchShouldEmit(fields, idx, map[int]bool{}) {
+			continue
+		}
+		if !found || idx < index {
+			index, trigger, found = idx, b, true
+		}
+	}
+
+	return index, trigger, found
 }
 
 // 将 obj 的所有可导出字段转换成 field 的描述形式，并以数组形式返回。
@@ -97,13 +227,49 @@ func getFields(obj interface{}) (map[int]*field, error) {
 	if v.Kind() != reflect.Struct {
 		return nil, errors.New("参数 obj 只能是结构体")
 	}
-	t := v.Type()
 
-	fields := make(map[int]*field, v.NumField())
-	for i := 0; i < v.NumField(); i++ {
-		name := t.Field(i).Name
+	schema, err := parseSchema(v.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[int]*field, len(schema))
+	for index, sf := range schema {
+		fields[index] = &field{
+			name:      sf.name,
+			Type:      sf.Type,
+			Routes:    sf.Routes,
+			Value:     v.Field(sf.structIndex),
+			sep:       sf.sep,
+			custom:    sf.custom,
+			validator: sf.validator,
+		}
+	}
+
+	return fields, nil
+}
+
+// schemaField 保存的是 version 标签中与具体实例无关的那部分字段元数据，
+// 可以在同一类型的多次 Parse 之间缓存复用，避免重复反射 tag。
+type schemaField struct {
+	name        string       // 字段名称
+	structIndex int          // 字段在结构体中的索引，用于取值
+	Type        int          // 该字段的类型，数值、字符串或是 idents
+	Routes      map[byte]int // 该字段的路由，根据不同的字符，会跳到不同的元素中解析
+	sep         string       // fieldTypeIdents 类型的分隔符
+	custom      bool         // 字段类型是否实现了 Unmarshaler 接口
+	validator
+}
+
+// parseSchema 解析 t 的 version 标签定义，返回与具体实例无关的字段
+// 元数据。
+func parseSchema(t reflect.Type) (map[int]*schemaField, error) {
+	fields := make(map[int]*schemaField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sft := t.Field(i)
+		name := sft.Name
 
-		tags := strings.Split(t.Field(i).Tag.Get("version"), ",")
+		tags := strings.Split(sft.Tag.Get("version"), ",")
 		if len(tags) < 2 {
 			return nil, fmt.Errorf("字段[%v]缺少必要的标签元素", name)
 		}
@@ -123,31 +289,46 @@ func getFields(obj interface{}) (map[int]*field, error) {
 		}
 
 		// tags[1]
-		field := &field{Routes: make(map[byte]int, 2), name: name}
+		sf := &schemaField{name: name, structIndex: i, Routes: make(map[byte]int, 2), sep: "."}
 		switch tags[1] {
 		case "number":
-			field.Type = fieldTypeNumber
+			sf.Type = fieldTypeNumber
 		case "string":
-			field.Type = fieldTypeString
+			sf.Type = fieldTypeString
+		case "idents":
+			sf.Type = fieldTypeIdents
 		default:
 			return nil, fmt.Errorf("字段[%v]包含无效的标签：%v", name, tags[1])
 		}
 
-		// tags[2...]
+		// 字段类型实现了 Unmarshaler 接口时，由该接口负责解析，
+		// 不再使用 tags[1] 声明的类型进行转换。
+		if reflect.PointerTo(sft.Type).Implements(unmarshalerType) {
+			sf.custom = true
+		}
+
+		// tags[2...]，可以是路由(如 .1)，也可以是 default=、optional、
+		// re=、min=、max=、sep= 等指令。
 		for _, v := range tags[2:] {
+			ok, err := parseValidatorTag(sf, name, v)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				continue
+			}
+
 			n, err := strconv.Atoi(v[1:])
 			if err != nil {
 				return nil, err
 			}
-			field.Routes[v[0]] = n
+			sf.Routes[v[0]] = n
 		}
 
-		field.Value = v.Field(i)
-
-		fields[index] = field
+		fields[index] = sf
 	}
 
-	if err := checkFields(fields); err != nil {
+	if err := checkSchemaFields(fields); err != nil {
 		return nil, err
 	}
 
@@ -155,11 +336,11 @@ func getFields(obj interface{}) (map[int]*field, error) {
 }
 
 // 检测每个元素中的路由项都能找到对应的元素。
-func checkFields(fields map[int]*field) error {
-	for _, field := range fields {
-		for b, index := range field.Routes {
+func checkSchemaFields(fields map[int]*schemaField) error {
+	for _, f := range fields {
+		for b, index := range f.Routes {
 			if _, found := fields[index]; !found {
-				return fmt.Errorf("字段[%v]对应的路由项[%v]的值不存在", field.name, b)
+				return fmt.Errorf("字段[%v]对应的路由项[%v]的值不存在", f.name, b)
 			}
 		}
 	}
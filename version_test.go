@@ -0,0 +1,88 @@
+// Copyright 2016 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package version
+
+import "testing"
+
+// docVersion 是包文档注释中给出的示例结构体，其中 Build 字段的 Go 类型
+// 改为与 number 类型相符的 int。
+type docVersion struct {
+	Major int `version:"0,number,.1,+2"`
+	Minor int `version:"1,number,.2"`
+	Build int `version:"2,number"`
+}
+
+func TestFormat_docExample(t *testing.T) {
+	v := &docVersion{}
+	if err := Parse(v, "1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Format(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "1.2.3" {
+		t.Fatalf("got %q, want %q", s, "1.2.3")
+	}
+}
+
+func TestFormat_roundTrip(t *testing.T) {
+	cases := []string{"1.2.3", "1.0.3", "1.2.0", "0.0.0"}
+
+	for _, ver := range cases {
+		v := &docVersion{}
+		if err := Parse(v, ver); err != nil {
+			t.Fatalf("Parse(%q): %v", ver, err)
+		}
+
+		s, err := Format(v)
+		if err != nil {
+			t.Fatalf("Format(%q): %v", ver, err)
+		}
+		if s != ver {
+			t.Fatalf("Format(Parse(%q)) = %q, want %q", ver, s, ver)
+		}
+	}
+}
+
+func TestFormat_optionalTrailingField(t *testing.T) {
+	cases := []string{"1.2.3", "1.2.3-alpha.1"}
+
+	for _, ver := range cases {
+		v := &semver{}
+		if err := Parse(v, ver); err != nil {
+			t.Fatalf("Parse(%q): %v", ver, err)
+		}
+
+		s, err := Format(v)
+		if err != nil {
+			t.Fatalf("Format(%q): %v", ver, err)
+		}
+		if s != ver {
+			t.Fatalf("Format(Parse(%q)) = %q, want %q", ver, s, ver)
+		}
+	}
+}
+
+type uintVersion struct {
+	Major uint8 `version:"0,number,.1"`
+	Minor uint  `version:"1,number"`
+}
+
+func TestFormat_uintField(t *testing.T) {
+	v := &uintVersion{}
+	if err := Parse(v, "1.2"); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Format(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "1.2" {
+		t.Fatalf("got %q, want %q", s, "1.2")
+	}
+}